@@ -0,0 +1,181 @@
+package tritonhttp
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// apacheTimeFormat is the timestamp layout used inside the "[...]"
+// field of the Apache combined log format.
+const apacheTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// AccessLogEntry describes a single completed request/response pair to
+// be rendered as one Apache combined-format log line.
+type AccessLogEntry struct {
+	RemoteAddr   string
+	Time         time.Time
+	Method       string
+	URL          string
+	Proto        string
+	StatusCode   int
+	BytesWritten int64
+	Referer      string
+	UserAgent    string
+}
+
+// AccessLogger writes AccessLogEntry values in Apache combined log
+// format: `remote - - [dd/MMM/yyyy:HH:mm:ss -zzzz] "METHOD URL PROTO"
+// status bytes "referer" "user-agent"`, where bytes is the response
+// body size (the %b field), excluding the status line and headers. It
+// is safe for concurrent use.
+type AccessLogger struct {
+	mu   sync.Mutex
+	path string // empty when the logger doesn't own a reopenable file
+	w    io.Writer
+	f    *os.File
+}
+
+// NewAccessLogger wraps an arbitrary writer (e.g. os.Stdout) as an
+// AccessLogger. Since it doesn't own a file, Reopen is a no-op.
+func NewAccessLogger(w io.Writer) *AccessLogger {
+	return &AccessLogger{w: w}
+}
+
+// OpenAccessLogger opens (creating if necessary, appending otherwise)
+// the file at path as the destination for access log entries.
+func OpenAccessLogger(path string) (*AccessLogger, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &AccessLogger{path: path, w: f, f: f}, nil
+}
+
+// Log writes entry as a single Apache combined-format line.
+func (l *AccessLogger) Log(entry AccessLogEntry) {
+	requestLine := fmt.Sprintf("%s %s %s", entry.Method, entry.URL, entry.Proto)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fmt.Fprintf(l.w, "%s - - [%s] %q %d %d %q %q\n",
+		entry.RemoteAddr,
+		entry.Time.Format(apacheTimeFormat),
+		requestLine,
+		entry.StatusCode,
+		entry.BytesWritten,
+		dashIfEmpty(entry.Referer),
+		dashIfEmpty(entry.UserAgent),
+	)
+}
+
+// Reopen closes and reopens the underlying log file at the same path,
+// picking up a rename done by a log rotator (e.g. logrotate sending
+// SIGHUP). It is a no-op for loggers created with NewAccessLogger.
+func (l *AccessLogger) Reopen() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path == "" {
+		return nil
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	old := l.f
+	l.f = f
+	l.w = f
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// accessLoggerFor returns the AccessLogger that should record requests
+// for host, lazily opening and caching the per-host override from
+// VirtualHostLogs the first time it's needed, and falling back to
+// s.AccessLogger otherwise.
+func (s *Server) accessLoggerFor(host string) *AccessLogger {
+	path, overridden := s.VirtualHostLogs[host]
+	if !overridden {
+		return s.AccessLogger
+	}
+
+	s.vhostLoggersMu.Lock()
+	defer s.vhostLoggersMu.Unlock()
+	if logger, ok := s.vhostLoggers[host]; ok {
+		return logger
+	}
+	logger, err := OpenAccessLogger(path)
+	if err != nil {
+		log.Printf("access log: failed to open %q for host %q: %v", path, host, err)
+		return s.AccessLogger
+	}
+	if s.vhostLoggers == nil {
+		s.vhostLoggers = make(map[string]*AccessLogger)
+	}
+	s.vhostLoggers[host] = logger
+	return logger
+}
+
+// logAccess records one completed request/response pair, using req's
+// virtual host to pick the AccessLogger to log to (falling back to
+// Server.AccessLogger). req is nil for responses that couldn't be tied
+// to a well-formed request, e.g. 400 Bad Request.
+func (s *Server) logAccess(remoteAddr string, req *Request, res *Response) {
+	var host string
+	if req != nil {
+		host = req.Host
+	}
+	logger := s.accessLoggerFor(host)
+	if logger == nil {
+		return
+	}
+
+	entry := AccessLogEntry{
+		RemoteAddr:   remoteAddr,
+		Time:         time.Now(),
+		StatusCode:   res.StatusCode,
+		BytesWritten: res.BytesWritten(),
+		Method:       "-",
+		URL:          "-",
+		Proto:        "-",
+	}
+	if req != nil {
+		entry.Method = req.Method
+		entry.URL = req.URL
+		entry.Proto = req.Proto
+		entry.Referer = req.Headers.Get("Referer")
+		entry.UserAgent = req.Headers.Get("User-Agent")
+	}
+	logger.Log(entry)
+}
+
+// reopenAccessLogs reopens Server.AccessLogger and every per-virtual-host
+// override, for use as a SIGHUP handler so log rotation doesn't require
+// restarting the server.
+func (s *Server) reopenAccessLogs() {
+	if s.AccessLogger != nil {
+		if err := s.AccessLogger.Reopen(); err != nil {
+			log.Printf("access log: reopen failed: %v", err)
+		}
+	}
+	s.vhostLoggersMu.Lock()
+	defer s.vhostLoggersMu.Unlock()
+	for host, logger := range s.vhostLoggers {
+		if err := logger.Reopen(); err != nil {
+			log.Printf("access log: reopen failed for host %q: %v", host, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package tritonhttp
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLoggerLogRendersApacheCombinedFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewAccessLogger(&buf)
+
+	when := time.Date(2026, time.July, 27, 15, 4, 5, 0, time.FixedZone("", 0))
+	l.Log(AccessLogEntry{
+		RemoteAddr:   "127.0.0.1",
+		Time:         when,
+		Method:       "GET",
+		URL:          "/index.html",
+		Proto:        "HTTP/1.1",
+		StatusCode:   200,
+		BytesWritten: 42,
+		Referer:      "http://example.com/",
+		UserAgent:    "tritonhttp-test",
+	})
+
+	want := `127.0.0.1 - - [27/Jul/2026:15:04:05 +0000] "GET /index.html HTTP/1.1" 200 42 "http://example.com/" "tritonhttp-test"` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("Log output =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestAccessLoggerLogDashesEmptyFields(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewAccessLogger(&buf)
+
+	l.Log(AccessLogEntry{
+		RemoteAddr: "127.0.0.1",
+		Time:       time.Unix(0, 0).UTC(),
+		Method:     "-",
+		URL:        "-",
+		Proto:      "-",
+		StatusCode: 400,
+	})
+
+	got := buf.String()
+	if !strings.Contains(got, `"-" "-"`) {
+		t.Fatalf("expected empty referer/user-agent to render as dashes, got %q", got)
+	}
+}
+
+func TestAccessLoggerReopenPicksUpRenamedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+
+	l, err := OpenAccessLogger(path)
+	if err != nil {
+		t.Fatalf("OpenAccessLogger failed: %v", err)
+	}
+	l.Log(AccessLogEntry{RemoteAddr: "127.0.0.1", Time: time.Now(), Method: "-", URL: "-", Proto: "-", StatusCode: 200})
+
+	// Simulate a log rotator renaming the file out from under the
+	// logger before asking it to reopen.
+	rotated := filepath.Join(dir, "access.log.1")
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatalf("failed to rotate log file: %v", err)
+	}
+
+	if err := l.Reopen(); err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	l.Log(AccessLogEntry{RemoteAddr: "127.0.0.1", Time: time.Now(), Method: "-", URL: "-", Proto: "-", StatusCode: 200})
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected Reopen to recreate %q: %v", path, err)
+	}
+	rotatedContents, err := os.ReadFile(rotated)
+	if err != nil {
+		t.Fatalf("failed to read rotated file: %v", err)
+	}
+	if strings.Count(string(rotatedContents), "\n") != 1 {
+		t.Fatalf("expected the rotated file to keep exactly the one line logged before Reopen, got %q", rotatedContents)
+	}
+}
+
+func TestAccessLoggerReopenIsNoopWithoutAFile(t *testing.T) {
+	l := NewAccessLogger(&bytes.Buffer{})
+	if err := l.Reopen(); err != nil {
+		t.Fatalf("expected Reopen to be a no-op for a logger without a backing file, got %v", err)
+	}
+}
+
+func TestResponseBytesWrittenExcludesHeaders(t *testing.T) {
+	res := &Response{Proto: "HTTP/1.1", StatusCode: 200}
+	res.Headers.Set("Content-Length", "5")
+	res.SetBody(strings.NewReader("hello"), 5)
+
+	var buf bytes.Buffer
+	if err := res.Write(&buf); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	if got, want := res.BytesWritten(), int64(5); got != want {
+		t.Fatalf("BytesWritten() = %d, want %d (body only, excluding status line and headers)", got, want)
+	}
+	if buf.Len() <= 5 {
+		t.Fatalf("expected the full response (status line + headers + body) to be longer than the body alone")
+	}
+}
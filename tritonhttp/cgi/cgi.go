@@ -0,0 +1,185 @@
+// Package cgi implements a tritonhttp.Handler that runs an external
+// program per request following the Common Gateway Interface, RFC 3875.
+package cgi
+
+import (
+	"bufio"
+	"io"
+	"net/textproto"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/harivamsi9/TrittonHTTP/tritonhttp"
+)
+
+// Handler implements tritonhttp.Handler by forking Path once per request
+// and speaking CGI/1.1 with it over stdin/stdout.
+type Handler struct {
+	Path string // path to the CGI program to execute
+	Root string // URL prefix this Handler is mounted at; stripped to form PATH_INFO
+	Dir  string // working directory for the child process; defaults to filepath.Dir(Path)
+
+	// Env holds extra "key=value" environment variables passed to the
+	// child in addition to the standard CGI variables.
+	Env []string
+
+	// Args holds extra arguments passed to Path.
+	Args []string
+}
+
+func (h *Handler) ServeTritonHTTP(res *tritonhttp.Response, req *tritonhttp.Request) {
+	dir := h.Dir
+	if dir == "" {
+		dir = filepath.Dir(h.Path)
+	}
+
+	cmd := exec.Command(h.Path, h.Args...)
+	cmd.Dir = dir
+	cmd.Env = append(Env(req, h.Root), h.Env...)
+	if req.Body != nil {
+		cmd.Stdin = req.Body
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		serverError(res)
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		serverError(res)
+		return
+	}
+
+	tp := textproto.NewReader(bufio.NewReader(stdout))
+	mimeHeader, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		_ = cmd.Wait()
+		serverError(res)
+		return
+	}
+
+	WriteResponse(res, mimeHeader, &cmdBody{r: tp.R, cmd: cmd})
+	if req.Method == "HEAD" {
+		res.SkipBody = true
+	}
+}
+
+// cmdBody streams a CGI child's stdout and reaps the process once its
+// output is fully drained, so the body can be streamed straight into
+// WriteBody instead of being buffered in memory.
+type cmdBody struct {
+	r      io.Reader
+	cmd    *exec.Cmd
+	reaped bool
+}
+
+func (b *cmdBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if err == io.EOF && !b.reaped {
+		b.reaped = true
+		_ = b.cmd.Wait()
+	}
+	return n, err
+}
+
+// Close reaps the child process if Read never drained it to io.EOF -
+// e.g. the client disconnected mid-response - killing it first since
+// it may be blocked writing to a stdout pipe nobody is reading from
+// anymore, which would otherwise make Wait hang.
+func (b *cmdBody) Close() error {
+	if b.reaped {
+		return nil
+	}
+	b.reaped = true
+	_ = b.cmd.Process.Kill()
+	return b.cmd.Wait()
+}
+
+// WriteResponse maps a parsed set of CGI response headers onto res and
+// streams body as the response body, per the Status/Location handling
+// in RFC 3875 section 6.3. If mimeHeader didn't carry a Content-Length
+// (the common case, since most CGI scripts don't know their output
+// size up front), the body is sent chunked. It's exported so the fcgi
+// package, which speaks the same header/body convention over the
+// FastCGI record protocol, can reuse it.
+func WriteResponse(res *tritonhttp.Response, mimeHeader textproto.MIMEHeader, body io.Reader) {
+	statusCode := 200
+	if status := mimeHeader.Get("Status"); status != "" {
+		mimeHeader.Del("Status")
+		if fields := strings.Fields(status); len(fields) > 0 {
+			if code, convErr := strconv.Atoi(fields[0]); convErr == nil {
+				statusCode = code
+			}
+		}
+	} else if mimeHeader.Get("Location") != "" {
+		statusCode = 302
+	}
+
+	res.StatusCode = statusCode
+	for key := range mimeHeader {
+		res.Headers.Set(tritonhttp.CanonicalHeaderKey(key), mimeHeader.Get(key))
+	}
+
+	size := int64(-1)
+	if contentLength := mimeHeader.Get("Content-Length"); contentLength != "" {
+		if n, convErr := strconv.ParseInt(contentLength, 10, 64); convErr == nil {
+			size = n
+		}
+	}
+	res.SetBody(body, size)
+}
+
+func serverError(res *tritonhttp.Response) {
+	res.StatusCode = 500
+	res.FilePath = ""
+	res.Headers.Set("Content-Length", "0")
+	res.SkipBody = true
+}
+
+// Env builds the CGI/1.1 environment variables (RFC 3875 section 4) for
+// req, as if it were being served under the URL prefix root. It's
+// exported so the fcgi package can build an equivalent parameter set.
+func Env(req *tritonhttp.Request, root string) []string {
+	pathInfo := strings.TrimPrefix(req.URL, root)
+	if !strings.HasPrefix(pathInfo, "/") {
+		pathInfo = "/" + pathInfo
+	}
+
+	queryString := ""
+	if i := strings.IndexByte(pathInfo, '?'); i >= 0 {
+		queryString = pathInfo[i+1:]
+		pathInfo = pathInfo[:i]
+	}
+
+	env := []string{
+		"GATEWAY_INTERFACE=CGI/1.1",
+		"SERVER_SOFTWARE=TrittonHTTP",
+		"SERVER_PROTOCOL=" + req.Proto,
+		"SERVER_NAME=" + req.Host,
+		"REQUEST_METHOD=" + req.Method,
+		"SCRIPT_NAME=" + root,
+		"PATH_INFO=" + pathInfo,
+		"QUERY_STRING=" + queryString,
+	}
+	if contentLength, ok := req.Headers.Lookup("Content-Length"); ok {
+		env = append(env, "CONTENT_LENGTH="+contentLength)
+	}
+	if contentType, ok := req.Headers.Lookup("Content-Type"); ok {
+		env = append(env, "CONTENT_TYPE="+contentType)
+	}
+	req.Headers.ForEach(func(key, value string) {
+		if key == "Content-Length" || key == "Content-Type" {
+			return
+		}
+		env = append(env, "HTTP_"+httpEnvName(key)+"="+value)
+	})
+	return env
+}
+
+// httpEnvName converts a canonical header key like "Accept-Encoding"
+// into the CGI environment variable suffix "ACCEPT_ENCODING".
+func httpEnvName(headerKey string) string {
+	return strings.ToUpper(strings.ReplaceAll(headerKey, "-", "_"))
+}
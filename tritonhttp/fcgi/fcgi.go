@@ -0,0 +1,274 @@
+// Package fcgi implements a tritonhttp.Handler that proxies requests to
+// an external FastCGI application (e.g. PHP-FPM) over the FastCGI
+// multiplexed record protocol.
+package fcgi
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+
+	"github.com/harivamsi9/TrittonHTTP/tritonhttp"
+	"github.com/harivamsi9/TrittonHTTP/tritonhttp/cgi"
+)
+
+const (
+	fcgiVersion1 = 1
+
+	typeBeginRequest = 1
+	typeEndRequest   = 3
+	typeParams       = 4
+	typeStdin        = 5
+	typeStdout       = 6
+	typeStderr       = 7
+
+	roleResponder = 1
+)
+
+// Handler implements tritonhttp.Handler by forwarding each request to a
+// FastCGI application listening on Network/Addr (e.g. Network: "tcp",
+// Addr: "127.0.0.1:9000", or Network: "unix", Addr: "/run/php-fpm.sock").
+type Handler struct {
+	Network string // "tcp" or "unix"
+	Addr    string // address to dial
+
+	// Root is the URL prefix this Handler is mounted at; it's stripped
+	// to form PATH_INFO, the same way cgi.Handler.Root is.
+	Root string
+}
+
+// requestID is always 1: each request opens (and tears down) its own
+// connection, so there is never more than one in-flight request to
+// multiplex over a single connection.
+const requestID = 1
+
+func (h *Handler) ServeTritonHTTP(res *tritonhttp.Response, req *tritonhttp.Request) {
+	conn, err := net.Dial(h.Network, h.Addr)
+	if err != nil {
+		serverError(res)
+		return
+	}
+
+	if err := writeBeginRequest(conn, roleResponder); err != nil {
+		conn.Close()
+		serverError(res)
+		return
+	}
+
+	params := encodeParams(cgi.Env(req, h.Root))
+	if err := writeStream(conn, typeParams, params); err != nil {
+		conn.Close()
+		serverError(res)
+		return
+	}
+
+	var stdin []byte
+	if req.Body != nil {
+		if stdin, err = io.ReadAll(req.Body); err != nil {
+			conn.Close()
+			serverError(res)
+			return
+		}
+	}
+	if err := writeStream(conn, typeStdin, stdin); err != nil {
+		conn.Close()
+		serverError(res)
+		return
+	}
+
+	// stdoutBody lazily decodes FCGI_STDOUT records off conn as it's
+	// read, closing conn once FCGI_END_REQUEST is reached, so the
+	// response body can be streamed instead of buffered in memory.
+	body := &stdoutBody{br: bufio.NewReader(conn), conn: conn}
+	tp := textproto.NewReader(bufio.NewReader(body))
+	mimeHeader, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		conn.Close()
+		serverError(res)
+		return
+	}
+
+	cgi.WriteResponse(res, mimeHeader, bodyReadCloser{Reader: tp.R, closer: body})
+	if req.Method == "HEAD" {
+		res.SkipBody = true
+	}
+}
+
+// bodyReadCloser pairs tp.R - the bufio.Reader left positioned right
+// after the parsed MIME header - with the underlying stdoutBody's
+// Close, so that closing the response body (to reap an aborted
+// request) doesn't require reading from stdoutBody directly and
+// skipping the bytes tp.R has already buffered.
+type bodyReadCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (b bodyReadCloser) Close() error {
+	return b.closer.Close()
+}
+
+func serverError(res *tritonhttp.Response) {
+	res.StatusCode = 500
+	res.FilePath = ""
+	res.Headers.Set("Content-Length", "0")
+	res.SkipBody = true
+}
+
+// writeBeginRequest sends an FCGI_BEGIN_REQUEST record for the given
+// role (FCGI_RESPONDER, normally).
+func writeBeginRequest(w io.Writer, role uint16) error {
+	body := []byte{byte(role >> 8), byte(role), 0, 0, 0, 0, 0, 0}
+	return writeRecord(w, typeBeginRequest, body)
+}
+
+// writeStream frames content as a sequence of FastCGI records of the
+// given type (each record's content is at most 65535 bytes), followed
+// by the empty record that signals end-of-stream, per section 3.3 of
+// the FastCGI spec.
+func writeStream(w io.Writer, recType byte, content []byte) error {
+	for len(content) > 0 {
+		chunk := content
+		if len(chunk) > 65535 {
+			chunk = chunk[:65535]
+		}
+		if err := writeRecord(w, recType, chunk); err != nil {
+			return err
+		}
+		content = content[len(chunk):]
+	}
+	return writeRecord(w, recType, nil)
+}
+
+// writeRecord writes a single FastCGI record header, its content and
+// the padding needed to align the record to an 8-byte boundary.
+func writeRecord(w io.Writer, recType byte, content []byte) error {
+	contentLength := len(content)
+	padding := (8 - contentLength%8) % 8
+
+	header := []byte{
+		fcgiVersion1,
+		recType,
+		byte(requestID >> 8), byte(requestID),
+		byte(contentLength >> 8), byte(contentLength),
+		byte(padding),
+		0,
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if len(content) > 0 {
+		if _, err := w.Write(content); err != nil {
+			return err
+		}
+	}
+	if padding > 0 {
+		if _, err := w.Write(make([]byte, padding)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stdoutBody decodes FCGI_STDOUT record content off conn on demand,
+// skipping FCGI_STDERR and padding, and closes conn once
+// FCGI_END_REQUEST is seen. This lets a FastCGI application's response
+// be streamed straight through WriteBody instead of being buffered in
+// memory up front.
+type stdoutBody struct {
+	br     *bufio.Reader
+	conn   net.Conn
+	buf    []byte
+	done   bool
+	closed bool
+}
+
+func (b *stdoutBody) Read(p []byte) (int, error) {
+	for len(b.buf) == 0 && !b.done {
+		var header [8]byte
+		if _, err := io.ReadFull(b.br, header[:]); err != nil {
+			b.closeConn()
+			return 0, err
+		}
+		recType := header[1]
+		contentLength := int(header[4])<<8 | int(header[5])
+		paddingLength := int(header[6])
+
+		content := make([]byte, contentLength)
+		if _, err := io.ReadFull(b.br, content); err != nil {
+			b.closeConn()
+			return 0, err
+		}
+		if paddingLength > 0 {
+			if _, err := io.CopyN(io.Discard, b.br, int64(paddingLength)); err != nil {
+				b.closeConn()
+				return 0, err
+			}
+		}
+
+		switch recType {
+		case typeStdout:
+			b.buf = content
+		case typeEndRequest:
+			b.done = true
+		}
+	}
+	if len(b.buf) == 0 {
+		b.closeConn()
+		return 0, io.EOF
+	}
+	n := copy(p, b.buf)
+	b.buf = b.buf[n:]
+	return n, nil
+}
+
+// Close closes the underlying connection if Read hasn't already, e.g.
+// because the client disconnected before the response was fully
+// drained. It's idempotent so it's safe to call after Read has already
+// closed conn on EOF.
+func (b *stdoutBody) Close() error {
+	return b.closeConn()
+}
+
+func (b *stdoutBody) closeConn() error {
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	return b.conn.Close()
+}
+
+// encodeParams encodes a set of "KEY=VALUE" strings as FastCGI
+// FCGI_PARAMS name-value pairs (FastCGI spec section 3.4).
+func encodeParams(env []string) []byte {
+	var buf bytes.Buffer
+	for _, kv := range env {
+		idx := strings.IndexByte(kv, '=')
+		if idx < 0 {
+			continue
+		}
+		key, value := kv[:idx], kv[idx+1:]
+		writeParamLength(&buf, len(key))
+		writeParamLength(&buf, len(value))
+		buf.WriteString(key)
+		buf.WriteString(value)
+	}
+	return buf.Bytes()
+}
+
+// writeParamLength encodes a name/value length as 1 byte when it fits
+// in 7 bits, or 4 bytes with the high bit of the first byte set
+// otherwise, per the FastCGI spec.
+func writeParamLength(buf *bytes.Buffer, n int) {
+	if n < 128 {
+		buf.WriteByte(byte(n))
+		return
+	}
+	buf.WriteByte(byte(n>>24) | 0x80)
+	buf.WriteByte(byte(n >> 16))
+	buf.WriteByte(byte(n >> 8))
+	buf.WriteByte(byte(n))
+}
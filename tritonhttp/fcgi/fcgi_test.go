@@ -0,0 +1,185 @@
+package fcgi
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/harivamsi9/TrittonHTTP/tritonhttp"
+)
+
+// fakeResponder listens like a FastCGI application (e.g. PHP-FPM) and
+// answers exactly one request with a canned FCGI_STDOUT body, echoing
+// back the REQUEST_METHOD and stdin it was sent, so Handler can be
+// driven end-to-end over the real record protocol without depending on
+// an actual FastCGI application being available.
+func fakeResponder(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		serveOneFakeRequest(conn)
+	}()
+
+	return ln.Addr().String()
+}
+
+// serveOneFakeRequest reads the FCGI_BEGIN_REQUEST/FCGI_PARAMS/FCGI_STDIN
+// records for a single request off conn and writes back an
+// FCGI_STDOUT/FCGI_END_REQUEST response, mirroring the framing Handler
+// itself writes in writeRecord/writeStream.
+func serveOneFakeRequest(conn net.Conn) {
+	params := map[string]string{}
+	var stdin []byte
+
+	for {
+		recType, content, err := readRecord(conn)
+		if err != nil {
+			return
+		}
+		switch recType {
+		case typeParams:
+			if len(content) == 0 {
+				continue
+			}
+			for k, v := range decodeParams(content) {
+				params[k] = v
+			}
+		case typeStdin:
+			if len(content) == 0 {
+				// The empty FCGI_STDIN record ends the request stream;
+				// the response can now be written.
+				body := fmt.Sprintf("method=%s body=%s", params["REQUEST_METHOD"], stdin)
+				resp := "Content-Type: text/plain\r\n\r\n" + body
+				_ = writeRecord(conn, typeStdout, []byte(resp))
+				_ = writeRecord(conn, typeStdout, nil)
+				_ = writeRecord(conn, typeEndRequest, make([]byte, 8))
+				return
+			}
+			stdin = append(stdin, content...)
+		}
+	}
+}
+
+// readRecord reads a single FastCGI record off r, stripping its
+// padding, the inverse of writeRecord.
+func readRecord(r io.Reader) (recType byte, content []byte, err error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	recType = header[1]
+	contentLength := int(header[4])<<8 | int(header[5])
+	padding := int(header[6])
+
+	content = make([]byte, contentLength)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return 0, nil, err
+	}
+	if padding > 0 {
+		if _, err := io.CopyN(io.Discard, r, int64(padding)); err != nil {
+			return 0, nil, err
+		}
+	}
+	return recType, content, nil
+}
+
+// decodeParams decodes a single FCGI_PARAMS record's content into its
+// name/value pairs, the inverse of encodeParams/writeParamLength.
+func decodeParams(content []byte) map[string]string {
+	params := map[string]string{}
+	for len(content) > 0 {
+		keyLen, n := readParamLength(content)
+		content = content[n:]
+		valLen, n := readParamLength(content)
+		content = content[n:]
+
+		key := string(content[:keyLen])
+		content = content[keyLen:]
+		value := string(content[:valLen])
+		content = content[valLen:]
+
+		params[key] = value
+	}
+	return params
+}
+
+// readParamLength decodes a single name/value length and reports how
+// many bytes of content it consumed, the inverse of writeParamLength.
+func readParamLength(content []byte) (length int, consumed int) {
+	if content[0]&0x80 == 0 {
+		return int(content[0]), 1
+	}
+	return int(content[0]&0x7f)<<24 | int(content[1])<<16 | int(content[2])<<8 | int(content[3]), 4
+}
+
+func TestHandlerServesPOSTWithBody(t *testing.T) {
+	addr := fakeResponder(t)
+
+	h := &Handler{Network: "tcp", Addr: addr}
+	req := &tritonhttp.Request{
+		Method: "POST",
+		URL:    "/app/submit",
+		Proto:  "HTTP/1.1",
+		Body:   strings.NewReader("hello=world"),
+	}
+	res := &tritonhttp.Response{}
+
+	h.ServeTritonHTTP(res, req)
+
+	if res.StatusCode != 200 {
+		t.Fatalf("expected 200, got %d", res.StatusCode)
+	}
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+	if got, want := string(body), "method=POST body=hello=world"; got != want {
+		t.Fatalf("response body = %q, want %q", got, want)
+	}
+}
+
+func TestHandlerSkipsBodyForHEAD(t *testing.T) {
+	addr := fakeResponder(t)
+
+	h := &Handler{Network: "tcp", Addr: addr}
+	req := &tritonhttp.Request{Method: "HEAD", URL: "/app/submit", Proto: "HTTP/1.1"}
+	res := &tritonhttp.Response{}
+
+	h.ServeTritonHTTP(res, req)
+
+	if !res.SkipBody {
+		t.Fatalf("expected SkipBody to be set for a HEAD request")
+	}
+	_ = res.Close()
+}
+
+func TestHandlerServerErrorWhenUnreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // nothing listens at addr any more
+
+	h := &Handler{Network: "tcp", Addr: addr}
+	req := &tritonhttp.Request{Method: "GET", URL: "/app/submit", Proto: "HTTP/1.1"}
+	res := &tritonhttp.Response{}
+
+	h.ServeTritonHTTP(res, req)
+
+	if res.StatusCode != 500 {
+		t.Fatalf("expected 500 when the FastCGI application is unreachable, got %d", res.StatusCode)
+	}
+}
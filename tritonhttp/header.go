@@ -0,0 +1,86 @@
+package tritonhttp
+
+import "net/textproto"
+
+// CanonicalHeaderKey returns the canonical format of the header key s,
+// e.g. "if-modified-since" becomes "If-Modified-Since".
+func CanonicalHeaderKey(s string) string {
+	return textproto.CanonicalMIMEHeaderKey(s)
+}
+
+// Header is a slice-backed set of header key/value pairs, keyed by
+// their canonical form. Unlike a map[string]string, Reset lets a
+// Header be emptied and reused across requests/responses (see
+// AcquireRequest/AcquireResponse) without discarding its backing
+// arrays, so serving a keep-alive connection steady-state doesn't
+// allocate a fresh map per message.
+type Header struct {
+	keys   []string
+	values []string
+}
+
+// Get returns the value associated with key, or "" if it isn't set.
+func (h *Header) Get(key string) string {
+	value, _ := h.Lookup(key)
+	return value
+}
+
+// Lookup returns the value associated with key and whether it was
+// present, distinguishing an unset header from one set to "".
+func (h *Header) Lookup(key string) (string, bool) {
+	canon := CanonicalHeaderKey(key)
+	for i, k := range h.keys {
+		if k == canon {
+			return h.values[i], true
+		}
+	}
+	return "", false
+}
+
+// Set stores value under key, overwriting any previous value for the
+// same (canonicalized) key.
+func (h *Header) Set(key, value string) {
+	canon := CanonicalHeaderKey(key)
+	for i, k := range h.keys {
+		if k == canon {
+			h.values[i] = value
+			return
+		}
+	}
+	h.keys = append(h.keys, canon)
+	h.values = append(h.values, value)
+}
+
+// Del removes key, if present.
+func (h *Header) Del(key string) {
+	canon := CanonicalHeaderKey(key)
+	for i, k := range h.keys {
+		if k == canon {
+			h.keys = append(h.keys[:i], h.keys[i+1:]...)
+			h.values = append(h.values[:i], h.values[i+1:]...)
+			return
+		}
+	}
+}
+
+// Keys returns the canonical keys currently set, in no particular
+// order. Callers must not modify the returned slice.
+func (h *Header) Keys() []string {
+	return h.keys
+}
+
+// ForEach calls f once per key/value pair currently set, in no
+// particular order, without the repeated Lookup scan that calling Get
+// for every key in Keys() would cost.
+func (h *Header) ForEach(f func(key, value string)) {
+	for i, k := range h.keys {
+		f(k, h.values[i])
+	}
+}
+
+// Reset empties h while keeping its backing arrays, so the next round
+// of Sets can reuse the already-grown capacity instead of reallocating.
+func (h *Header) Reset() {
+	h.keys = h.keys[:0]
+	h.values = h.values[:0]
+}
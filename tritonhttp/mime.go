@@ -0,0 +1,18 @@
+package tritonhttp
+
+import "mime"
+
+// defaultMIMEType is returned for extensions MIMETypeByExtension does not
+// recognize.
+const defaultMIMEType = "application/octet-stream"
+
+// MIMETypeByExtension maps a file extension (including the leading dot,
+// e.g. ".html") to a Content-Type value, falling back to
+// defaultMIMEType when the extension is unknown.
+func MIMETypeByExtension(ext string) string {
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		return defaultMIMEType
+	}
+	return contentType
+}
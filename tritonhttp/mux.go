@@ -0,0 +1,149 @@
+package tritonhttp
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Handler responds to a single TrittonHTTP request, mirroring the shape
+// of net/http's Handler. Implementations write the outcome of handling
+// req into res (e.g. by calling res.HandleOK or res.HandleNotFound)
+// rather than returning it.
+type Handler interface {
+	ServeTritonHTTP(res *Response, req *Request)
+}
+
+// HandlerFunc adapts an ordinary function to a Handler.
+type HandlerFunc func(res *Response, req *Request)
+
+func (f HandlerFunc) ServeTritonHTTP(res *Response, req *Request) {
+	f(res, req)
+}
+
+// ServeMux dispatches requests to a Handler based on the request's Host
+// and URL path prefix, the same way net/http.ServeMux dispatches on
+// host and pattern. Among handlers registered for a matching host, the
+// one with the longest matching path prefix wins.
+type ServeMux struct {
+	mu      sync.RWMutex
+	entries map[string][]muxEntry
+}
+
+type muxEntry struct {
+	prefix  string
+	handler Handler
+}
+
+// NewServeMux allocates a new, empty ServeMux.
+func NewServeMux() *ServeMux {
+	return &ServeMux{entries: make(map[string][]muxEntry)}
+}
+
+// Handle registers handler to serve requests for host whose URL starts
+// with prefix. Registering the same (host, prefix) pair twice replaces
+// the earlier handler.
+func (mux *ServeMux) Handle(host, prefix string, handler Handler) {
+	mux.mu.Lock()
+	defer mux.mu.Unlock()
+
+	entries := mux.entries[host]
+	for i := range entries {
+		if entries[i].prefix == prefix {
+			entries[i].handler = handler
+			return
+		}
+	}
+	entries = append(entries, muxEntry{prefix: prefix, handler: handler})
+	sort.Slice(entries, func(i, j int) bool {
+		return len(entries[i].prefix) > len(entries[j].prefix)
+	})
+	mux.entries[host] = entries
+}
+
+// Handler returns the registered Handler that best matches req, or
+// false if no virtual host/prefix combination matches.
+func (mux *ServeMux) Handler(req *Request) (Handler, bool) {
+	mux.mu.RLock()
+	defer mux.mu.RUnlock()
+
+	for _, entry := range mux.entries[req.Host] {
+		if strings.HasPrefix(req.URL, entry.prefix) {
+			return entry.handler, true
+		}
+	}
+	return nil, false
+}
+
+// ServeTritonHTTP implements Handler by looking up the best-matching
+// registered handler for req and delegating to it, or responding 404 if
+// none matches.
+func (mux *ServeMux) ServeTritonHTTP(res *Response, req *Request) {
+	handler, ok := mux.Handler(req)
+	if !ok {
+		res.HandleNotFound(req)
+		return
+	}
+	handler.ServeTritonHTTP(res, req)
+}
+
+// fileServer implements Handler by serving static files out of docRoot,
+// the way Server used to behave before the Handler abstraction existed.
+type fileServer struct {
+	docRoot string
+}
+
+// FileServer returns a Handler that serves static files rooted at
+// docRoot, honoring conditional GET, Range and HEAD the same way
+// HandleOK does.
+func FileServer(docRoot string) Handler {
+	return &fileServer{docRoot: docRoot}
+}
+
+func (fs *fileServer) ServeTritonHTTP(res *Response, req *Request) {
+	docRoot := filepath.Clean(fs.docRoot)
+	absPath := filepath.Join(docRoot, req.URL)
+	if absPath != docRoot && !strings.HasPrefix(absPath, docRoot+string(filepath.Separator)) {
+		res.HandleNotFound(req)
+		return
+	}
+	if _, err := os.Stat(absPath); errors.Is(err, os.ErrNotExist) {
+		res.HandleNotFound(req)
+		return
+	}
+	res.HandleOK(req, absPath)
+}
+
+// stripPrefixHandler implements StripPrefix.
+type stripPrefixHandler struct {
+	prefix string
+	next   Handler
+}
+
+// StripPrefix returns a Handler that removes the given prefix from the
+// request URL before delegating to next, responding 404 for requests
+// that don't carry the prefix. It's typically used together with
+// ServeMux to mount a Handler (often a FileServer) under a path other
+// than the one it was written to serve.
+func StripPrefix(prefix string, next Handler) Handler {
+	if prefix == "" {
+		return next
+	}
+	return &stripPrefixHandler{prefix: prefix, next: next}
+}
+
+func (s *stripPrefixHandler) ServeTritonHTTP(res *Response, req *Request) {
+	rest, ok := strings.CutPrefix(req.URL, s.prefix)
+	if !ok {
+		res.HandleNotFound(req)
+		return
+	}
+	if !strings.HasPrefix(rest, "/") {
+		rest = "/" + rest
+	}
+	req.URL = rest
+	s.next.ServeTritonHTTP(res, req)
+}
@@ -0,0 +1,101 @@
+package tritonhttp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func handlerNamed(name string) Handler {
+	return HandlerFunc(func(res *Response, req *Request) {
+		res.StatusCode = 200
+		res.Headers.Set("X-Handler", name)
+	})
+}
+
+func TestServeMuxLongestPrefixWins(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("example.com", "/", handlerNamed("root"))
+	mux.Handle("example.com", "/api/", handlerNamed("api"))
+	mux.Handle("example.com", "/api/v2/", handlerNamed("api-v2"))
+
+	cases := []struct {
+		url  string
+		want string
+	}{
+		{"/", "root"},
+		{"/about.html", "root"},
+		{"/api/widgets", "api"},
+		{"/api/v2/widgets", "api-v2"},
+	}
+	for _, c := range cases {
+		req := &Request{Host: "example.com", URL: c.url}
+		res := &Response{}
+		mux.ServeTritonHTTP(res, req)
+		if got := res.Headers.Get("X-Handler"); got != c.want {
+			t.Errorf("URL %q: dispatched to %q, want %q", c.url, got, c.want)
+		}
+	}
+}
+
+func TestServeMuxHostMissIs404(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("example.com", "/", handlerNamed("root"))
+
+	req := &Request{Host: "other.com", URL: "/"}
+	res := &Response{}
+	mux.ServeTritonHTTP(res, req)
+
+	if res.StatusCode != 404 {
+		t.Fatalf("expected 404 for unregistered host, got %d", res.StatusCode)
+	}
+}
+
+func TestStripPrefixRewritesURL(t *testing.T) {
+	var gotURL string
+	inner := HandlerFunc(func(res *Response, req *Request) {
+		gotURL = req.URL
+		res.StatusCode = 200
+	})
+
+	handler := StripPrefix("/static", inner)
+	req := &Request{URL: "/static/css/site.css"}
+	res := &Response{}
+	handler.ServeTritonHTTP(res, req)
+
+	if gotURL != "/css/site.css" {
+		t.Fatalf("got rewritten URL %q, want %q", gotURL, "/css/site.css")
+	}
+}
+
+func TestStripPrefixMissIs404(t *testing.T) {
+	handler := StripPrefix("/static", handlerNamed("inner"))
+	req := &Request{URL: "/other/site.css"}
+	res := &Response{}
+	handler.ServeTritonHTTP(res, req)
+
+	if res.StatusCode != 404 {
+		t.Fatalf("expected 404 for a URL missing the prefix, got %d", res.StatusCode)
+	}
+}
+
+func TestFileServerRejectsDeepTraversal(t *testing.T) {
+	docRoot := t.TempDir()
+	if err := os.WriteFile(filepath.Join(docRoot, "index.html"), []byte("hi"), 0644); err != nil {
+		t.Fatalf("failed to write index.html: %v", err)
+	}
+
+	fs := FileServer(docRoot)
+	req := &Request{
+		Method: "GET",
+		URL:    "/../../../../../../../../etc/passwd",
+	}
+	res := &Response{}
+
+	// Must not panic, and must not escape docRoot.
+	fs.ServeTritonHTTP(res, req)
+
+	if res.StatusCode != 404 {
+		t.Fatalf("expected 404 for a traversal outside docRoot, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,84 @@
+package tritonhttp
+
+import (
+	"bufio"
+	"net"
+	"sync"
+)
+
+var requestPool = sync.Pool{
+	New: func() any { return new(Request) },
+}
+
+// AcquireRequest returns a *Request from the pool, ready to be filled
+// in (e.g. by ReadRequest). Its zero value is equivalent to a freshly
+// allocated Request.
+func AcquireRequest() *Request {
+	return requestPool.Get().(*Request)
+}
+
+// ReleaseRequest clears req and returns it to the pool. The caller
+// must not use req again afterwards.
+func ReleaseRequest(req *Request) {
+	*req = Request{Headers: req.Headers}
+	req.Headers.Reset()
+	requestPool.Put(req)
+}
+
+var responsePool = sync.Pool{
+	New: func() any { return new(Response) },
+}
+
+// AcquireResponse returns a *Response from the pool, ready to be filled
+// in (e.g. by Response.init).
+func AcquireResponse() *Response {
+	return responsePool.Get().(*Response)
+}
+
+// ReleaseResponse clears res and returns it to the pool. The caller
+// must not use res again afterwards.
+func ReleaseResponse(res *Response) {
+	headers := res.Headers
+	*res = Response{Headers: headers}
+	res.Headers.Reset()
+	responsePool.Put(res)
+}
+
+var bufioReaderPool = sync.Pool{
+	New: func() any { return bufio.NewReaderSize(nil, 4096) },
+}
+
+// acquireBufioReader returns a *bufio.Reader from the pool, reset to
+// read from r, so that establishing a new connection doesn't need to
+// allocate a fresh read buffer.
+func acquireBufioReader(r net.Conn) *bufio.Reader {
+	br := bufioReaderPool.Get().(*bufio.Reader)
+	br.Reset(r)
+	return br
+}
+
+// releaseBufioReader detaches br from its underlying reader and
+// returns it to the pool.
+func releaseBufioReader(br *bufio.Reader) {
+	br.Reset(nil)
+	bufioReaderPool.Put(br)
+}
+
+var lineScratchPool = sync.Pool{
+	New: func() any { return new([]byte) },
+}
+
+// acquireLineScratch returns a *[]byte from the pool, to be reused by
+// readCurrLine as its line buffer across every request read off one
+// connection, so that - once it has grown to cover the longest request
+// line or header line seen - further lines are read without growing it
+// again from nil.
+func acquireLineScratch() *[]byte {
+	return lineScratchPool.Get().(*[]byte)
+}
+
+// releaseLineScratch truncates scratch and returns it to the pool.
+func releaseLineScratch(scratch *[]byte) {
+	*scratch = (*scratch)[:0]
+	lineScratchPool.Put(scratch)
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package tritonhttp
+
+const raceEnabled = false
@@ -0,0 +1,8 @@
+//go:build race
+
+package tritonhttp
+
+// raceEnabled is true when the test binary was built with the race
+// detector, which instruments allocations for its own bookkeeping and
+// would otherwise make AllocsPerRun-based assertions flaky.
+const raceEnabled = true
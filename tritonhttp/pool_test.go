@@ -0,0 +1,143 @@
+package tritonhttp
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestPoolZeroAllocs exercises the steady-state keep-alive cycle of
+// acquiring a Request and a Response from their pools, populating
+// headers, and releasing them, which is the part of request handling
+// that AcquireRequest/AcquireResponse and the slice-backed Header type
+// were introduced to make allocation-free. It does not cover
+// disk/time-formatting work done elsewhere in the handler (e.g.
+// os.Stat, FormatTime), which still allocate.
+func TestPoolZeroAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector instruments allocations, making AllocsPerRun unreliable")
+	}
+
+	// Warm up the pools so the first AllocsPerRun iteration isn't
+	// charged for growing the Header slices from nil.
+	warm := AcquireRequest()
+	warm.Headers.Set("Host", "example.com")
+	warm.Headers.Set("User-Agent", "tritonhttp-bench")
+	ReleaseRequest(warm)
+
+	warmRes := AcquireResponse()
+	warmRes.Headers.Set("Content-Type", "text/html")
+	warmRes.Headers.Set("Content-Length", "0")
+	ReleaseResponse(warmRes)
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		req := AcquireRequest()
+		req.Method = "GET"
+		req.URL = "/"
+		req.Proto = "HTTP/1.1"
+		req.Headers.Set("Host", "example.com")
+		req.Headers.Set("User-Agent", "tritonhttp-bench")
+
+		res := AcquireResponse()
+		res.Headers.Set("Content-Type", "text/html")
+		res.Headers.Set("Content-Length", "0")
+		_ = res.Headers.Get("Content-Type")
+
+		ReleaseResponse(res)
+		ReleaseRequest(req)
+	})
+
+	if allocs > 0 {
+		t.Fatalf("expected zero allocations per served request on a warm pool, got %v", allocs)
+	}
+}
+
+// maxEndToEndAllocsPerRequest bounds TestReadWriteAllocs. The backlog
+// asked for this benchmark to assert zero allocations for a served GET
+// on a warm pool; that is only partially true here. acquireLineScratch
+// lets readCurrLine reuse its line buffer across requests on the same
+// connection instead of growing a new one from nil each time, which
+// TestReadWriteAllocs exercises below - but every line's string(...)
+// conversion (and WriteSortedHeaders' per-request "key: value\r\n"
+// string builds) still copies once, since Request/Header are
+// string-typed throughout. Removing those too would mean parsing and
+// serializing through []byte instead of string end to end, which is a
+// larger rework of Request/Header than this fix covers. So this bound
+// is a regression guard on the real, nonzero cost, not the zero-alloc
+// claim the backlog asked for.
+const maxEndToEndAllocsPerRequest = 20
+
+// TestReadWriteAllocs drives a "GET / HTTP/1.1" request through
+// ReadRequest and the matching response through Write, reusing the
+// same lineScratch buffer across iterations the way
+// Server.HandleCurrentConnection reuses one per connection, and
+// asserts the allocation count stays within maxEndToEndAllocsPerRequest.
+// Unlike TestPoolZeroAllocs, which only measures pool reuse, this
+// exercises the real parse/serialize path so a regression there (e.g.
+// readCurrLine or header serialization starting to allocate more) is
+// actually caught.
+func TestReadWriteAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector instruments allocations, making AllocsPerRun unreliable")
+	}
+
+	const rawRequest = "GET / HTTP/1.1\r\nHost: example.com\r\nUser-Agent: tritonhttp-bench\r\n\r\n"
+
+	// Warm up the pools the same way TestPoolZeroAllocs does, so the
+	// first AllocsPerRun iteration isn't charged for growing the
+	// Header slices from nil.
+	warm := AcquireRequest()
+	warm.Headers.Set("Host", "example.com")
+	warm.Headers.Set("User-Agent", "tritonhttp-bench")
+	ReleaseRequest(warm)
+
+	warmRes := AcquireResponse()
+	warmRes.Headers.Set("Content-Type", "text/html")
+	warmRes.Headers.Set("Content-Length", "0")
+	ReleaseResponse(warmRes)
+
+	// Reuse one bufio.Reader across iterations (resetting it to the
+	// start of rawRequest each time) the same way a real connection's
+	// cur_buffer is acquired once per connection, not once per request,
+	// so the benchmark isn't charged for reallocating the read buffer
+	// itself on every served request.
+	rawRequestBytes := []byte(rawRequest)
+	src := bytes.NewReader(rawRequestBytes)
+	br := bufio.NewReaderSize(src, 4096)
+
+	lineScratch := acquireLineScratch()
+	defer releaseLineScratch(lineScratch)
+	// Warm lineScratch to its high-water mark too, the same way a
+	// connection's first request would, so AllocsPerRun isn't charged
+	// for growing it from nil either.
+	if _, _, err := ReadRequest(br, 0, lineScratch); err != nil {
+		t.Fatalf("warm-up ReadRequest: %v", err)
+	}
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		src.Reset(rawRequestBytes)
+		br.Reset(src)
+		req, _, err := ReadRequest(br, 0, lineScratch)
+		if err != nil {
+			t.Fatalf("ReadRequest: %v", err)
+		}
+
+		res := AcquireResponse()
+		res.init(req)
+		res.StatusCode = 200
+		res.SkipBody = true
+		res.Headers.Set("Content-Length", "0")
+
+		if err := res.Write(io.Discard); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+
+		ReleaseResponse(res)
+		ReleaseRequest(req)
+	})
+
+	if allocs > maxEndToEndAllocsPerRequest {
+		t.Fatalf("expected at most %v allocations per served request, got %v", maxEndToEndAllocsPerRequest, allocs)
+	}
+}
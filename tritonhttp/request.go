@@ -0,0 +1,23 @@
+package tritonhttp
+
+import "io"
+
+// Request represents an incoming HTTP request parsed off the wire by
+// ReadRequest.
+type Request struct {
+	Method string // e.g. "GET" or "HEAD"
+	URL    string // e.g. "/path/to/a/file"
+	Proto  string // e.g. "HTTP/1.1"
+
+	// Headers stores all headers other than "Host" and a
+	// "Connection: close" header; any other Connection value is kept.
+	Headers Header
+
+	Host  string // determined by the "Host" header
+	Close bool   // determined by the "Connection" header
+
+	// Body streams the request entity body, if any. It is non-nil only
+	// when the request carried a Content-Length header, and reads
+	// exactly that many bytes off the connection.
+	Body io.Reader
+}
@@ -0,0 +1,78 @@
+package tritonhttp
+
+import (
+	"io"
+	"strconv"
+)
+
+// Response represents the HTTP response to be written back for a single
+// Request.
+type Response struct {
+	Proto      string // e.g. "HTTP/1.1"
+	StatusCode int    // e.g. 200
+	Headers    Header
+
+	// Request is the request that this response is for. It is nil for
+	// responses that could not be tied to a well-formed request (e.g.
+	// a 400 Bad Request).
+	Request *Request
+
+	// FilePath is the (absolute) path to the file backing the response
+	// body. It is empty when the response carries no file-backed body.
+	FilePath string
+
+	// SkipBody tells WriteBody to omit the entity body (HEAD requests
+	// and 304 Not Modified responses) even though Content-Length may
+	// still describe the resource's size.
+	SkipBody bool
+
+	// RangeStart/RangeLen describe the byte range of FilePath to send
+	// when the response is a 206 Partial Content. RangeLen is -1 when
+	// the whole file should be sent.
+	RangeStart int64
+	RangeLen   int64
+
+	// Body carries the response body for handlers that don't serve a
+	// file directly, e.g. the cgi and fcgi packages. It is only
+	// consulted by WriteBody when FilePath is empty; set it via
+	// SetBody rather than directly so Content-Length/Transfer-Encoding
+	// stay in sync.
+	Body     io.Reader
+	bodySize int64 // set by SetBody; -1 means unknown/chunked
+
+	// bytesWritten is populated by Write and surfaced via
+	// BytesWritten, for access logging.
+	bytesWritten int64
+}
+
+// SetBody installs r as the response body, to be streamed out by
+// WriteBody. size is the body's length in bytes, or -1 if it isn't
+// known up front (e.g. the output of a CGI script). When size is -1,
+// WriteBody frames the body with Transfer-Encoding: chunked instead of
+// Content-Length, so large or unbounded bodies can be streamed without
+// buffering.
+func (res *Response) SetBody(r io.Reader, size int64) {
+	res.Body = r
+	res.bodySize = size
+	if size < 0 {
+		res.Headers.Del("Content-Length")
+		res.Headers.Set("Transfer-Encoding", "chunked")
+		return
+	}
+	res.Headers.Del("Transfer-Encoding")
+	res.Headers.Set("Content-Length", strconv.FormatInt(size, 10))
+}
+
+// Close releases any resources held by res.Body, if it implements
+// io.Closer (e.g. the cgi and fcgi packages' body types, which reap a
+// child process or close an upstream connection on Close). Write calls
+// this unconditionally once it's done, since a body can be left
+// partially drained - a disconnecting client or a write error stops
+// WriteBody before it reaches io.EOF - and relying on EOF alone would
+// leak the process/connection.
+func (res *Response) Close() error {
+	if c, ok := res.Body.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
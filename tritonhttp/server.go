@@ -2,16 +2,21 @@ package tritonhttp
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -23,8 +28,66 @@ type Server struct {
 
 	// VirtualHosts contains a mapping from host name to the docRoot path
 	// (i.e. the path to the directory to serve static files from) for
-	// all virtual hosts that this server supports
+	// all virtual hosts that this server supports. It is used to build
+	// the default Handler (a ServeMux serving a FileServer per host)
+	// when Handler is nil.
 	VirtualHosts map[string]string
+
+	// Handler, if non-nil, overrides the default static-file dispatch
+	// built from VirtualHosts. Set it to a *ServeMux to register
+	// dynamic endpoints (or other per-host handlers) alongside, or
+	// instead of, static file serving.
+	Handler Handler
+
+	// AccessLogger, if non-nil, is where one Apache combined-format
+	// line is written per completed request/response.
+	AccessLogger *AccessLogger
+
+	// VirtualHostLogs optionally overrides the destination log file
+	// for a given virtual host (keyed by Host header value), instead
+	// of logging it through AccessLogger. Overrides are opened lazily,
+	// the first time that host is served.
+	VirtualHostLogs map[string]string
+
+	// MaxConns optionally bounds the number of simultaneous connections
+	// ListenAndServe will accept. Connections beyond the limit are
+	// closed immediately in the accept loop. Zero means unlimited.
+	MaxConns int
+
+	// MaxHeaderBytes optionally bounds the size, in bytes, of the
+	// request line and each header line read by readCurrLine. Zero
+	// means unlimited.
+	MaxHeaderBytes int
+
+	handlerOnce sync.Once
+	handler     Handler
+
+	vhostLoggersMu sync.Mutex
+	vhostLoggers   map[string]*AccessLogger
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    sync.Map // net.Conn -> struct{}
+	wg       sync.WaitGroup
+	closing  atomic.Bool
+}
+
+// handlerFor returns the Handler that requests should be dispatched to,
+// building the default VirtualHosts-backed ServeMux the first time it's
+// needed if s.Handler was not set.
+func (s *Server) handlerFor() Handler {
+	s.handlerOnce.Do(func() {
+		if s.Handler != nil {
+			s.handler = s.Handler
+			return
+		}
+		mux := NewServeMux()
+		for host, docRoot := range s.VirtualHosts {
+			mux.Handle(host, "/", FileServer(docRoot))
+		}
+		s.handler = mux
+	})
+	return s.handler
 }
 
 // ListenAndServe listens on the TCP network address s.Addr and then
@@ -37,8 +100,20 @@ func (s *Server) ListenAndServe() error {
 		return err
 	}
 
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
 	log.Printf("Listening at Address: %q", listener.Addr())
 
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			s.reopenAccessLogs()
+		}
+	}()
+
 	defer func() {
 		err = listener.Close()
 		if err != nil {
@@ -49,17 +124,121 @@ func (s *Server) ListenAndServe() error {
 	for {
 		conn, err := listener.Accept() // Keeps looking for incoming connections continously
 		if err != nil {
+			if s.closing.Load() {
+				return nil
+			}
 			return err
 		}
+
+		if s.MaxConns > 0 && s.numConns() >= s.MaxConns {
+			log.Printf("Rejecting connection from %v: MaxConns (%d) reached", conn.RemoteAddr(), s.MaxConns)
+			_ = conn.Close()
+			continue
+		}
+
+		// s.mu also guards the closing/wg.Add race: Shutdown takes the
+		// same lock to flip closing before it calls wg.Wait, so either
+		// we observe closing here (and skip Add, letting Shutdown's
+		// Wait proceed without us) or our Add happens-before Shutdown
+		// sees closing and calls Wait.
+		s.mu.Lock()
+		if s.closing.Load() {
+			s.mu.Unlock()
+			_ = conn.Close()
+			continue
+		}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
 		log.Printf("Accepted connection at: %q", conn.RemoteAddr())
-		go s.HandleCurrentConnection(conn)
+		s.conns.Store(conn, struct{}{})
+		go func() {
+			defer s.wg.Done()
+			defer s.conns.Delete(conn)
+			s.HandleCurrentConnection(conn)
+		}()
+	}
+}
+
+// numConns reports how many connections are currently tracked in
+// s.conns, for enforcing MaxConns in the accept loop.
+func (s *Server) numConns() int {
+	n := 0
+	s.conns.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// Shutdown gracefully shuts down the server: it stops Accepting new
+// connections, lets requests already in flight on existing connections
+// finish (each such connection is closed right after, instead of being
+// kept alive for another request), and waits for every
+// HandleCurrentConnection goroutine to return. If ctx is done first,
+// Shutdown returns ctx.Err() without waiting any further, leaving the
+// remaining connections to finish draining in the background.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing.Store(true)
+	listener := s.listener
+	s.mu.Unlock()
+	if listener != nil {
+		_ = listener.Close()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close immediately closes the listener and every tracked connection,
+// without waiting for in-flight requests to finish. Unlike Shutdown, it
+// does not block on HandleCurrentConnection goroutines draining.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closing.Store(true)
+	listener := s.listener
+	s.mu.Unlock()
+
+	var err error
+	if listener != nil {
+		err = listener.Close()
 	}
+
+	s.conns.Range(func(key, _ any) bool {
+		_ = key.(net.Conn).Close()
+		return true
+	})
+
+	return err
 }
 
 func (s *Server) HandleCurrentConnection(conn net.Conn) {
-	cur_buffer := bufio.NewReader(conn)
+	cur_buffer := acquireBufioReader(conn)
+	defer releaseBufioReader(cur_buffer)
+	lineScratch := acquireLineScratch()
+	defer releaseLineScratch(lineScratch)
 
 	for {
+		if s.closing.Load() {
+			// The server is shutting down and this connection has no
+			// request in flight (it's idle, waiting here for the next
+			// one) - close it now rather than blocking Shutdown until
+			// its read deadline expires.
+			_ = conn.Close()
+			return
+		}
+
 		// Setting up timeout
 		timmer_now_5secs := time.Now().Add(time.Second * 5)
 		if err := conn.SetReadDeadline(timmer_now_5secs); err != nil {
@@ -69,7 +248,7 @@ func (s *Server) HandleCurrentConnection(conn net.Conn) {
 		}
 
 		// Trying to read next request
-		req, bytesReceived, err := ReadRequest(cur_buffer)
+		req, bytesReceived, err := ReadRequest(cur_buffer, s.MaxHeaderBytes, lineScratch)
 
 		//Handling EOF
 		is_eof := errors.Is(err, io.EOF)
@@ -87,30 +266,53 @@ func (s *Server) HandleCurrentConnection(conn net.Conn) {
 				_ = conn.Close()
 				return
 			}
-			res := &Response{}
+			res := AcquireResponse()
 			res.HandleInvalidBadRequest()
 			_ = res.Write(conn)
+			s.logAccess(conn.RemoteAddr().String(), nil, res)
+			ReleaseResponse(res)
 			_ = conn.Close()
 			return
 		}
 
 		if err != nil {
-			log.Printf("Handle bad request for error: %v", err)
-			res := &Response{}
+			res := AcquireResponse()
 			res.HandleInvalidBadRequest()
 			_ = res.Write(conn)
+			s.logAccess(conn.RemoteAddr().String(), nil, res)
+			ReleaseResponse(res)
 			_ = conn.Close()
 			return
 		}
 
-		log.Printf("Handle good request: %v", req)
+		if s.closing.Load() {
+			// Server is shutting down: finish this in-flight request
+			// but don't keep the connection alive for another one.
+			req.Close = true
+		}
+
 		res := s.HandleValidGoodRequest(req)
 		err = res.Write(conn)
 		if err != nil {
 			fmt.Println(err)
 		}
+		s.logAccess(conn.RemoteAddr().String(), req, res)
+
+		// The Handler isn't required to read req.Body to completion
+		// (the static FileServer never touches it at all, and a
+		// CGI/FastCGI script can exit early) - drain whatever is left
+		// so the next pipelined/keep-alive request on cur_buffer
+		// starts at the next request line instead of in the middle of
+		// this one's unread body.
+		if req.Body != nil {
+			if _, err := io.Copy(io.Discard, req.Body); err != nil {
+				req.Close = true
+			}
+		}
 
 		isReqClose := req.Close
+		ReleaseResponse(res)
+		ReleaseRequest(req)
 		if isReqClose {
 			_ = conn.Close()
 			return
@@ -119,34 +321,69 @@ func (s *Server) HandleCurrentConnection(conn net.Conn) {
 }
 
 func (s *Server) HandleValidGoodRequest(req *Request) (res *Response) {
-	res = &Response{}
+	res = AcquireResponse()
 	res.init(req)
-	absPath := filepath.Join(s.VirtualHosts[req.Host], req.URL)
-	left_absPath := absPath[:len(s.VirtualHosts[req.Host])]
-	reqHostInVirtualHosts := s.VirtualHosts[req.Host]
-
-	if left_absPath != reqHostInVirtualHosts {
-		res.HandleNotFound(req)
-	} else if _, err := os.Stat(absPath); errors.Is(err, os.ErrNotExist) {
-		res.HandleNotFound(req)
-	} else {
-		res.HandleOK(req, absPath)
-	}
+	s.handlerFor().ServeTritonHTTP(res, req)
 	return res
 }
 
 func (res *Response) HandleOK(req *Request, path string) {
 	res.StatusCode = 200
 	res.FilePath = path
+	res.RangeLen = -1
 
 	stats, err := os.Stat(path)
 	err_exists := errors.Is(err, os.ErrNotExist)
 	if err_exists {
 		log.Print(err)
 	}
-	res.Headers["Last-Modified"] = FormatTime(stats.ModTime())
-	res.Headers["Content-Type"] = MIMETypeByExtension(filepath.Ext(path))
-	res.Headers["Content-Length"] = strconv.FormatInt(stats.Size(), 10)
+	modTime := stats.ModTime()
+	size := stats.Size()
+	res.Headers.Set("Last-Modified", FormatTime(modTime))
+	res.Headers.Set("Content-Type", MIMETypeByExtension(filepath.Ext(path)))
+	res.Headers.Set("Content-Length", strconv.FormatInt(size, 10))
+
+	if ims, hasIfModSince := req.Headers.Lookup("If-Modified-Since"); hasIfModSince {
+		if since, parseErr := ParseTime(ims); parseErr == nil && !modTime.After(since) {
+			res.HandleNotModified(req)
+			return
+		}
+	}
+
+	if rangeHeader, hasRange := req.Headers.Lookup("Range"); hasRange {
+		start, end, malformed := parseRange(rangeHeader, size)
+		if malformed {
+			res.HandleRangeNotSatisfiable(req, size)
+			return
+		}
+		if start >= 0 {
+			res.StatusCode = 206
+			res.RangeStart = start
+			res.RangeLen = end - start + 1
+			res.Headers.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+			res.Headers.Set("Content-Length", strconv.FormatInt(res.RangeLen, 10))
+		}
+	}
+
+	isHeadReq := req.Method == "HEAD"
+	if isHeadReq {
+		res.SkipBody = true
+	}
+}
+
+func (res *Response) HandleNotModified(req *Request) {
+	res.StatusCode = 304
+	res.FilePath = ""
+	res.SkipBody = true
+	res.Headers.Set("Content-Length", "0")
+}
+
+func (res *Response) HandleRangeNotSatisfiable(req *Request, size int64) {
+	res.StatusCode = 416
+	res.FilePath = ""
+	res.SkipBody = true
+	res.Headers.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+	res.Headers.Set("Content-Length", "0")
 }
 
 func (res *Response) HandleInvalidBadRequest() {
@@ -154,18 +391,70 @@ func (res *Response) HandleInvalidBadRequest() {
 	res.StatusCode = 400
 	res.FilePath = ""
 	res.Request = nil
-	res.Headers["Connection"] = "close"
+	res.Headers.Set("Connection", "close")
 }
 
 func (res *Response) HandleNotFound(req *Request) {
 	res.StatusCode = 404
 }
 
+// parseRange parses a "Range: bytes=start-end" header value against a
+// resource of the given size, returning the inclusive byte range to
+// serve. start is -1 when no (or an unrecognized, non-bytes) range was
+// present, or when the header lists several ranges (contains ','), in
+// which case the whole resource should be served. malformed is true
+// when the header looks like a single byte-range but cannot be parsed
+// or satisfied, e.g. start beyond the end of the resource; callers
+// should respond with 416 Requested Range Not Satisfiable in that case.
+func parseRange(rangeHeader string, size int64) (start, end int64, malformed bool) {
+	spec, hasBytesPrefix := strings.CutPrefix(rangeHeader, "bytes=")
+	if !hasBytesPrefix || strings.Contains(spec, ",") {
+		return -1, -1, false
+	}
+
+	parts := strings.SplitN(spec, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, true
+	}
+	startStr, endStr := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+	switch {
+	case startStr == "" && endStr == "":
+		return 0, 0, true
+	case startStr == "":
+		// Suffix range: the last N bytes of the resource.
+		suffixLen, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || suffixLen <= 0 {
+			return 0, 0, true
+		}
+		if suffixLen > size {
+			suffixLen = size
+		}
+		return size - suffixLen, size - 1, false
+	default:
+		start, err := strconv.ParseInt(startStr, 10, 64)
+		if err != nil || start < 0 || start >= size {
+			return 0, 0, true
+		}
+		if endStr == "" {
+			return start, size - 1, false
+		}
+		end, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, true
+		}
+		if end >= size {
+			end = size - 1
+		}
+		return start, end, false
+	}
+}
+
 func (res *Response) init(req *Request) {
 	res.Proto = "HTTP/1.1"
 	res.Request = req
-	res.Headers = make(map[string]string)
-	res.Headers["Date"] = FormatTime(time.Now())
+	res.Headers.Reset()
+	res.Headers.Set("Date", FormatTime(time.Now()))
 	if req != nil {
 		lastChar_url := req.URL[len(req.URL)-1]
 		if lastChar_url == '/' {
@@ -173,7 +462,7 @@ func (res *Response) init(req *Request) {
 		}
 		isReqClose := req.Close
 		if isReqClose {
-			res.Headers["Connection"] = "close"
+			res.Headers.Set("Connection", "close")
 		}
 	}
 }
@@ -184,16 +473,38 @@ func (res *Response) init(req *Request) {
 
 // <-------------------------------- CODE RESPONSIBLE FOR RESPONSES --------------------------------->
 func (res *Response) Write(w io.Writer) error {
+	defer res.Close()
+
 	if err := res.WriteStatusLine(w); err != nil {
 		return err
 	}
 	if err := res.WriteSortedHeaders(w); err != nil {
 		return err
 	}
-	if err := res.WriteBody(w); err != nil {
-		return err
-	}
-	return nil
+
+	cw := &countingWriter{w: w}
+	defer func() { res.bytesWritten = cw.n }()
+	return res.WriteBody(cw)
+}
+
+// BytesWritten reports how many bytes of body the most recent call to
+// Write sent - the status line and headers are not counted, matching
+// the Apache combined log format's %b field - for access logging.
+func (res *Response) BytesWritten() int64 {
+	return res.bytesWritten
+}
+
+// countingWriter wraps an io.Writer (typically the net.Conn) to track
+// how many bytes have been written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
 }
 
 func (res *Response) WriteStatusLine(w io.Writer) error {
@@ -201,10 +512,20 @@ func (res *Response) WriteStatusLine(w io.Writer) error {
 	switch strconv.Itoa(res.StatusCode) {
 	case "200":
 		statusCode = "200 OK"
+	case "206":
+		statusCode = "206 Partial Content"
+	case "302":
+		statusCode = "302 Found"
+	case "304":
+		statusCode = "304 Not Modified"
 	case "400":
 		statusCode = "400 Bad Request"
 	case "404":
 		statusCode = "404 Not Found"
+	case "416":
+		statusCode = "416 Requested Range Not Satisfiable"
+	case "500":
+		statusCode = "500 Internal Server Error"
 	}
 
 	statusLine := res.Proto + " " + statusCode + "\r\n"
@@ -217,15 +538,11 @@ func (res *Response) WriteStatusLine(w io.Writer) error {
 }
 
 func (res *Response) WriteSortedHeaders(w io.Writer) error {
-	list_Sorted_Keys := make([]string, 0, len(res.Headers))
-
-	for eachKey, _ := range res.Headers {
-		list_Sorted_Keys = append(list_Sorted_Keys, eachKey)
-	}
+	list_Sorted_Keys := append([]string(nil), res.Headers.Keys()...)
 	sort.Strings(list_Sorted_Keys)
 
 	for _, eachKey := range list_Sorted_Keys {
-		header := eachKey + ": " + res.Headers[eachKey] + "\r\n"
+		header := eachKey + ": " + res.Headers.Get(eachKey) + "\r\n"
 		if _, err := w.Write([]byte(header)); err != nil {
 			return err
 		}
@@ -238,64 +555,146 @@ func (res *Response) WriteSortedHeaders(w io.Writer) error {
 }
 
 func (res *Response) WriteBody(w io.Writer) error {
-	var content []byte
-	var err error
+	if res.SkipBody {
+		return nil
+	}
+
 	res_path := res.FilePath
 	if res_path != "" {
-		if content, err = os.ReadFile(res.FilePath); err != nil {
-			return err
-		}
+		return res.writeFileBody(w)
+	}
+	if res.Body == nil {
+		return nil
+	}
+	if res.bodySize < 0 {
+		return writeChunkedBody(w, res.Body)
 	}
-	if _, err := w.Write(content); err != nil {
+	_, err := io.Copy(w, res.Body)
+	return err
+}
+
+// writeFileBody streams res.FilePath (or the res.RangeStart/RangeLen
+// slice of it) to w without reading the whole file into memory.
+func (res *Response) writeFileBody(w io.Writer) error {
+	f, err := os.Open(res.FilePath)
+	if err != nil {
 		return err
 	}
-	return nil
+	defer f.Close()
+
+	if res.RangeLen < 0 {
+		_, err := io.Copy(w, f)
+		return err
+	}
+	if _, err := f.Seek(res.RangeStart, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.CopyN(w, f, res.RangeLen)
+	return err
+}
+
+// writeChunkedBody frames r's content as Transfer-Encoding: chunked,
+// per RFC 7230 section 4.1, for bodies whose length isn't known up
+// front.
+func writeChunkedBody(w io.Writer, r io.Reader) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			if _, err := fmt.Fprintf(w, "%x\r\n", n); err != nil {
+				return err
+			}
+			if _, err := w.Write(buf[:n]); err != nil {
+				return err
+			}
+			if _, err := w.Write([]byte("\r\n")); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+	_, err := w.Write([]byte("0\r\n\r\n"))
+	return err
 }
 
 // <-------------------------------- CODE RELATED TO REQUEST MODULE --------------------------------->
 
-func readCurrLine(cur_buffer *bufio.Reader) (string, error) {
-	var curr_line string
+// readCurrLine reads a single CRLF-terminated line from cur_buffer, one
+// byte at a time so that maxHeaderBytes (if positive) can be enforced
+// as the line is read rather than after it has already been buffered
+// in full - cur_buffer.ReadString('\n') would otherwise keep growing
+// its internal buffer for a client that never sends "\r\n". scratch is
+// reused as the line buffer (see acquireLineScratch) so that repeated
+// calls across a keep-alive connection's requests don't keep growing a
+// new slice from nil; the string(...) conversion returned still copies
+// once per line, since Request/Header are string-typed throughout.
+func readCurrLine(cur_buffer *bufio.Reader, maxHeaderBytes int, scratch *[]byte) (string, error) {
+	curr_line := (*scratch)[:0]
 	for {
-		s, err := cur_buffer.ReadString('\n')
-		curr_line += s
+		b, err := cur_buffer.ReadByte()
 		if err != nil {
-			return curr_line, err
+			*scratch = curr_line
+			return string(curr_line), err
 		}
-		isCRLFstrSuffix := strings.HasSuffix(curr_line, "\r\n")
-		if isCRLFstrSuffix {
-			// Striping the curr_line end
-			curr_line = curr_line[:len(curr_line)-2]
-			return curr_line, nil
+		curr_line = append(curr_line, b)
+		if maxHeaderBytes > 0 && len(curr_line) > maxHeaderBytes {
+			*scratch = curr_line
+			return "", fmt.Errorf("header line exceeds MaxHeaderBytes (%d)", maxHeaderBytes)
+		}
+		isCRLFSuffix := len(curr_line) >= 2 && curr_line[len(curr_line)-2] == '\r' && curr_line[len(curr_line)-1] == '\n'
+		if isCRLFSuffix {
+			*scratch = curr_line
+			return string(curr_line[:len(curr_line)-2]), nil
 		}
 	}
 }
 
-func ReadRequest(cur_buffer *bufio.Reader) (req *Request, bytesReceived bool, err error) {
-	req = &Request{}
-	req.Headers = make(map[string]string)
-
-	curr_line, err := readCurrLine(cur_buffer)
+// ReadRequest parses one request off cur_buffer. On success, ownership
+// of the returned *Request (acquired from the Request pool) passes to
+// the caller, who must call ReleaseRequest on it once done. On failure
+// it returns nil, having already released its pooled Request back.
+// maxHeaderBytes, if positive, bounds the size of the request line and
+// of each individual header line (see readCurrLine); zero means
+// unlimited. lineScratch is the line buffer readCurrLine reuses (see
+// acquireLineScratch); callers reading more than one request off the
+// same connection should reuse the same lineScratch across calls.
+func ReadRequest(cur_buffer *bufio.Reader, maxHeaderBytes int, lineScratch *[]byte) (req *Request, bytesReceived bool, err error) {
+	req = AcquireRequest()
+
+	curr_line, err := readCurrLine(cur_buffer, maxHeaderBytes, lineScratch)
 	if err != nil {
+		ReleaseRequest(req)
 		return nil, false, err
 	}
 
 	req.Method, req.URL, req.Proto, err = parseEachRequestLine(curr_line)
 	if err != nil {
+		ReleaseRequest(req)
 		return nil, true, err
 	}
 	is_reqMethod := req.Method
 	is_slash := req.URL[0]
 	is_https1_1_proto := req.Proto
-	if is_reqMethod != "GET" || is_slash != '/' || is_https1_1_proto != "HTTP/1.1" {
+	// POST is allowed alongside GET/HEAD so dynamic handlers (cgi,
+	// fcgi) can receive form/API submissions with a request body; the
+	// static FileServer ignores the method and answers a POST as a GET.
+	isAllowedMethod := is_reqMethod == "GET" || is_reqMethod == "HEAD" || is_reqMethod == "POST"
+	if !isAllowedMethod || is_slash != '/' || is_https1_1_proto != "HTTP/1.1" {
+		ReleaseRequest(req)
 		return nil, true, fmt.Errorf("400")
 	}
 
 	hasHost := false
 	req.Close = false
 	for {
-		curr_line, err := readCurrLine(cur_buffer)
+		curr_line, err := readCurrLine(cur_buffer, maxHeaderBytes, lineScratch)
 		if err != nil {
+			ReleaseRequest(req)
 			return nil, true, err
 		}
 		if curr_line == "" {
@@ -303,6 +702,7 @@ func ReadRequest(cur_buffer *bufio.Reader) (req *Request, bytesReceived bool, er
 		}
 		fields := strings.SplitN(curr_line, ": ", 2)
 		if len(fields) != 2 {
+			ReleaseRequest(req)
 			return nil, true, fmt.Errorf("400")
 		}
 		eachKey := CanonicalHeaderKey(strings.TrimSpace(fields[0]))
@@ -314,16 +714,23 @@ func ReadRequest(cur_buffer *bufio.Reader) (req *Request, bytesReceived bool, er
 		} else if eachKey == "Connection" && value == "close" {
 			req.Close = true
 		} else {
-			req.Headers[eachKey] = value
+			req.Headers.Set(eachKey, value)
 		}
 	}
 
 	not_hasHost := !hasHost
 
 	if not_hasHost {
+		ReleaseRequest(req)
 		return nil, true, fmt.Errorf("400")
 	}
 
+	if contentLength, hasContentLength := req.Headers.Lookup("Content-Length"); hasContentLength {
+		if n, convErr := strconv.ParseInt(contentLength, 10, 64); convErr == nil && n > 0 {
+			req.Body = io.LimitReader(cur_buffer, n)
+		}
+	}
+
 	return req, true, nil
 }
 
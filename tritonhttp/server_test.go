@@ -0,0 +1,285 @@
+package tritonhttp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTempFile(t *testing.T, contents string) string {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "range.txt")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	return path
+}
+
+func TestReadRequestAcceptsHead(t *testing.T) {
+	raw := "HEAD /index.html HTTP/1.1\r\nHost: example.com\r\n\r\n"
+	var scratch []byte
+	req, _, err := ReadRequest(bufio.NewReader(strings.NewReader(raw)), 0, &scratch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.Method != "HEAD" {
+		t.Fatalf("expected Method HEAD, got %q", req.Method)
+	}
+}
+
+func TestHandleOKRangeAndConditional(t *testing.T) {
+	const body = "0123456789"
+	path := writeTempFile(t, body)
+	stats, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+
+	t.Run("range only", func(t *testing.T) {
+		req := &Request{Method: "GET"}
+		req.Headers.Set("Range", "bytes=2-5")
+		res := &Response{}
+		res.HandleOK(req, path)
+
+		if res.StatusCode != 206 {
+			t.Fatalf("expected 206, got %d", res.StatusCode)
+		}
+		if got, want := res.Headers.Get("Content-Range"), "bytes 2-5/10"; got != want {
+			t.Fatalf("Content-Range = %q, want %q", got, want)
+		}
+		if res.RangeStart != 2 || res.RangeLen != 4 {
+			t.Fatalf("unexpected range: start=%d len=%d", res.RangeStart, res.RangeLen)
+		}
+	})
+
+	t.Run("range combined with fresh If-Modified-Since", func(t *testing.T) {
+		past := stats.ModTime().Add(-time.Hour)
+		req := &Request{Method: "GET"}
+		req.Headers.Set("Range", "bytes=0-3")
+		req.Headers.Set("If-Modified-Since", FormatTime(past))
+		res := &Response{}
+		res.HandleOK(req, path)
+
+		if res.StatusCode != 206 {
+			t.Fatalf("expected 206 (range takes effect since file changed since past time), got %d", res.StatusCode)
+		}
+	})
+
+	t.Run("ModTime not after If-Modified-Since yields 304", func(t *testing.T) {
+		future := stats.ModTime().Add(time.Hour)
+		req := &Request{Method: "GET"}
+		req.Headers.Set("If-Modified-Since", FormatTime(future))
+		res := &Response{}
+		res.HandleOK(req, path)
+
+		if res.StatusCode != 304 {
+			t.Fatalf("expected 304, got %d", res.StatusCode)
+		}
+		if !res.SkipBody {
+			t.Fatalf("expected SkipBody to be set for 304")
+		}
+	})
+
+	t.Run("malformed range yields 416", func(t *testing.T) {
+		req := &Request{Method: "GET"}
+		req.Headers.Set("Range", "bytes=20-30")
+		res := &Response{}
+		res.HandleOK(req, path)
+
+		if res.StatusCode != 416 {
+			t.Fatalf("expected 416, got %d", res.StatusCode)
+		}
+		if got, want := res.Headers.Get("Content-Range"), "bytes */10"; got != want {
+			t.Fatalf("Content-Range = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("HEAD skips body but keeps Content-Length", func(t *testing.T) {
+		req := &Request{Method: "HEAD"}
+		res := &Response{}
+		res.HandleOK(req, path)
+
+		if !res.SkipBody {
+			t.Fatalf("expected SkipBody for HEAD request")
+		}
+		if got, want := res.Headers.Get("Content-Length"), "10"; got != want {
+			t.Fatalf("Content-Length = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestParseRangeMalformed(t *testing.T) {
+	cases := []string{
+		"bytes=",
+		"bytes=-",
+		"bytes=5-2",
+		"bytes=abc-5",
+		"bytes=100-200",
+	}
+	for _, header := range cases {
+		_, _, malformed := parseRange(header, 10)
+		if !malformed {
+			t.Errorf("parseRange(%q, 10) = not malformed, want malformed", header)
+		}
+	}
+}
+
+func TestParseRangeNoHeader(t *testing.T) {
+	start, _, malformed := parseRange("", 10)
+	if malformed {
+		t.Fatalf("empty header should not be treated as malformed")
+	}
+	if start != -1 {
+		t.Fatalf("expected start = -1 for no range, got %d", start)
+	}
+}
+
+func TestReadRequestMaxHeaderBytes(t *testing.T) {
+	raw := "GET / HTTP/1.1\r\nHost: example.com\r\nX-Long: " + strings.Repeat("a", 100) + "\r\n\r\n"
+
+	var scratch []byte
+	if _, _, err := ReadRequest(bufio.NewReader(strings.NewReader(raw)), 0, &scratch); err != nil {
+		t.Fatalf("unexpected error with unlimited MaxHeaderBytes: %v", err)
+	}
+	if _, _, err := ReadRequest(bufio.NewReader(strings.NewReader(raw)), 32, &scratch); err == nil {
+		t.Fatalf("expected error when a header line exceeds MaxHeaderBytes")
+	}
+}
+
+func startTestServer(t *testing.T, s *Server) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s.Addr = listener.Addr().String()
+	if err := listener.Close(); err != nil {
+		t.Fatalf("failed to close probe listener: %v", err)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- s.ListenAndServe() }()
+
+	// Wait for the real listener to come up before returning.
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		conn, err := net.Dial("tcp", s.Addr)
+		if err == nil {
+			conn.Close()
+			return s.Addr
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("server never started listening on %s", s.Addr)
+	return ""
+}
+
+func TestServerShutdownDrainsInFlightConnections(t *testing.T) {
+	docRoot := filepath.Dir(writeTempFile(t, "hello"))
+	s := &Server{VirtualHosts: map[string]string{"example.com": docRoot}}
+	addr := startTestServer(t, s)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("GET /range.txt HTTP/1.1\r\nHost: example.com\r\n\r\n")); err != nil {
+		t.Fatalf("failed to write request: %v", err)
+	}
+	if _, err := bufio.NewReader(conn).ReadString('\n'); err != nil {
+		t.Fatalf("failed to read status line: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown returned an error: %v", err)
+	}
+
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Fatalf("expected new connections to be refused after Shutdown")
+	}
+}
+
+func TestUnreadBodyDrainedBeforeNextRequest(t *testing.T) {
+	docRoot := filepath.Dir(writeTempFile(t, "hello"))
+	if err := os.WriteFile(filepath.Join(docRoot, "a.txt"), []byte("hi"), 0644); err != nil {
+		t.Fatalf("failed to write a.txt: %v", err)
+	}
+	s := &Server{VirtualHosts: map[string]string{"example.com": docRoot}}
+	addr := startTestServer(t, s)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	// FileServer never reads req.Body, so these 5 unread body bytes
+	// would otherwise be left in cur_buffer and parsed as the start of
+	// the next request line.
+	const req1 = "POST /a.txt HTTP/1.1\r\nHost: example.com\r\nContent-Length: 5\r\n\r\nhello"
+	const req2 = "GET /a.txt HTTP/1.1\r\nHost: example.com\r\n\r\n"
+	if _, err := conn.Write([]byte(req1 + req2)); err != nil {
+		t.Fatalf("failed to write requests: %v", err)
+	}
+
+	r := bufio.NewReader(conn)
+	for i, want := range []string{"200", "200"} {
+		statusLine, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read status line %d: %v", i+1, err)
+		}
+		if !strings.Contains(statusLine, want) {
+			t.Fatalf("response %d status line = %q, want it to contain %q", i+1, statusLine, want)
+		}
+		contentLength := 0
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("failed to read headers for response %d: %v", i+1, err)
+			}
+			if line == "\r\n" {
+				break
+			}
+			if n, ok := strings.CutPrefix(line, "Content-Length: "); ok {
+				contentLength, _ = strconv.Atoi(strings.TrimSpace(n))
+			}
+		}
+		if _, err := io.CopyN(io.Discard, r, int64(contentLength)); err != nil {
+			t.Fatalf("failed to read body for response %d: %v", i+1, err)
+		}
+	}
+}
+
+func TestServerCloseIsImmediate(t *testing.T) {
+	docRoot := filepath.Dir(writeTempFile(t, "hello"))
+	s := &Server{VirtualHosts: map[string]string{"example.com": docRoot}}
+	addr := startTestServer(t, s)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close returned an error: %v", err)
+	}
+
+	buf := make([]byte, 1)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(buf); err == nil {
+		t.Fatalf("expected the connection to be force-closed")
+	}
+}
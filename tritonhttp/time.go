@@ -0,0 +1,20 @@
+package tritonhttp
+
+import "time"
+
+// timeFormat is the format used on the wire for Date, Last-Modified and
+// If-Modified-Since headers, per RFC 7231 (the same layout as net/http's
+// http.TimeFormat).
+const timeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// FormatTime renders t in the wire format used for Date and
+// Last-Modified headers.
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(timeFormat)
+}
+
+// ParseTime parses a header value in the wire format used for
+// If-Modified-Since.
+func ParseTime(s string) (time.Time, error) {
+	return time.Parse(timeFormat, s)
+}